@@ -0,0 +1,104 @@
+// Copyright 2016, Ales Stimec.
+
+// Package apierror provides a single place for the demo's handler
+// packages to classify and write errors, so every service reports
+// VerificationError, DischargeRequired, Forbidden, BadRequest and
+// internal failures the same way, as a standard httpbakery client
+// expects.
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/juju/errors"
+	"github.com/juju/httprequest"
+	"gopkg.in/macaroon-bakery.v1/httpbakery"
+	"gopkg.in/macaroon.v1"
+)
+
+// Code classifies an error the way httpbakery clients expect to see it
+// in the Code field of the JSON error body.
+type Code string
+
+const (
+	// CodeBadRequest means the request itself was malformed.
+	CodeBadRequest Code = "bad request"
+	// CodeForbidden means the caller is not allowed to perform the
+	// requested operation, even after presenting valid macaroons.
+	CodeForbidden Code = "forbidden"
+	// CodeUnauthorized means the caller could not be authorized, for
+	// example because a discharge could not be obtained.
+	CodeUnauthorized Code = "unauthorized"
+	// CodeNotFound means the referenced resource does not exist.
+	CodeNotFound Code = "not found"
+	// CodeTimeout means the operation did not complete in time.
+	CodeTimeout Code = "timeout"
+)
+
+// statusCodes maps each Code to the HTTP status it is reported with.
+var statusCodes = map[Code]int{
+	CodeBadRequest:   http.StatusBadRequest,
+	CodeForbidden:    http.StatusForbidden,
+	CodeUnauthorized: http.StatusUnauthorized,
+	CodeNotFound:     http.StatusNotFound,
+	CodeTimeout:      http.StatusGatewayTimeout,
+}
+
+// Error is an error classified with one of the Code constants above.
+// Handlers should return one of these (or wrap one with errors.Trace)
+// instead of writing a status code directly.
+type Error struct {
+	error
+	// Code classifies the error.
+	Code Code
+}
+
+// Newf returns an error with the given code, formatted like fmt.Errorf.
+func Newf(code Code, format string, args ...interface{}) error {
+	return &Error{error: errors.Errorf(format, args...), Code: code}
+}
+
+// ErrorMapper classifies errors returned by the demo handlers into the
+// HTTP status and httpbakery.Error body that should be written in
+// response. Errors that are not *apierror.Error or *httpbakery.Error
+// are reported as internal server errors.
+var ErrorMapper httprequest.ErrorMapper = errToResp
+
+// WriteError writes err to w using ErrorMapper.
+func WriteError(w http.ResponseWriter, err error) {
+	ErrorMapper.WriteError(w, err)
+}
+
+// WriteDischargeRequiredError writes the discharge-required error for a
+// freshly minted macaroon m. Handlers should call this instead of
+// calling httpbakery.WriteDischargeRequiredErrorForRequest directly, so
+// every error response a handler writes - classified apierror.Error or
+// discharge requirement alike - goes through this package.
+func WriteDischargeRequiredError(w http.ResponseWriter, m *macaroon.Macaroon, path string, originalErr error, req *http.Request) {
+	httpbakery.WriteDischargeRequiredErrorForRequest(w, m, path, originalErr, req)
+}
+
+func errToResp(err error) (int, interface{}) {
+	cause := errors.Cause(err)
+	// A *httpbakery.Error, such as the one returned by
+	// httpbakery.NewInteractionRequiredError, already carries the
+	// Code and Info a bakery client expects; pass it straight through
+	// rather than flattening it into an internal server error.
+	if bakeryErr, ok := cause.(*httpbakery.Error); ok {
+		return http.StatusUnauthorized, bakeryErr
+	}
+	apiErr, ok := cause.(*Error)
+	if !ok {
+		return http.StatusInternalServerError, &httpbakery.Error{
+			Message: err.Error(),
+		}
+	}
+	status, ok := statusCodes[apiErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return status, &httpbakery.Error{
+		Message: apiErr.Error(),
+		Code:    httpbakery.ErrorCode(apiErr.Code),
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2016, Ales Stimec.
+
+// Package caveats lets services declare per-operation authorization
+// policies as data, rather than assembling []checkers.Caveat literals by
+// hand in every checkUser/checkStudent/checkProfessor-style function.
+//
+// A Policy binds an operation name to a Requirement, which knows how to
+// produce the caveats a freshly minted macaroon must carry to satisfy
+// it. Requirements compose with RequireAll and RequireAny, and services
+// that need to enforce request-bound constraints such as client address
+// or HTTP method can add them with IPPrefix and HTTPMethod, checked at
+// verification time with RequestChecker.
+package caveats
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
+	"gopkg.in/macaroon.v1"
+)
+
+const (
+	// ipPrefixCond is the first party caveat condition checked by
+	// RequestChecker against the address the request was made from.
+	ipPrefixCond = "client-ip-prefix"
+	// httpMethodCond is the first party caveat condition checked by
+	// RequestChecker against the request's HTTP method.
+	httpMethodCond = "http-method"
+)
+
+// Requirement produces the caveats a macaroon must carry to satisfy
+// some part of a Policy.
+type Requirement interface {
+	Caveats() []checkers.Caveat
+}
+
+// caveat adapts a single checkers.Caveat to the Requirement interface.
+type caveat checkers.Caveat
+
+func (c caveat) Caveats() []checkers.Caveat {
+	return []checkers.Caveat{checkers.Caveat(c)}
+}
+
+// ThirdParty returns a Requirement for a third party caveat addressed to
+// location, with the declared value bound to declaredName.
+func ThirdParty(location, condition, declaredName string) Requirement {
+	return caveat(checkers.NeedDeclaredCaveat(checkers.Caveat{Location: location, Condition: condition}, declaredName))
+}
+
+// TimeBefore returns a Requirement that the macaroon must not be used
+// after d has elapsed. The deadline is computed each time Caveats is
+// called, so it counts from when the macaroon is minted.
+func TimeBefore(d time.Duration) Requirement {
+	return timeBefore(d)
+}
+
+// timeBefore is the Requirement returned by TimeBefore.
+type timeBefore time.Duration
+
+func (d timeBefore) Caveats() []checkers.Caveat {
+	return []checkers.Caveat{checkers.TimeBeforeCaveat(time.Now().Add(time.Duration(d)))}
+}
+
+// IPPrefix returns a Requirement restricting use of the macaroon to
+// clients whose address falls within prefix, e.g. "10.0.0.0/8". It is
+// only enforced by a checker returned from RequestChecker.
+func IPPrefix(prefix string) Requirement {
+	return caveat(checkers.Caveat{Condition: ipPrefixCond + " " + prefix})
+}
+
+// HTTPMethod returns a Requirement restricting use of the macaroon to
+// requests made with the given HTTP method. It is only enforced by a
+// checker returned from RequestChecker.
+func HTTPMethod(method string) Requirement {
+	return caveat(checkers.Caveat{Condition: httpMethodCond + " " + method})
+}
+
+// all is a Requirement satisfied only when every one of its members is.
+type all []Requirement
+
+// RequireAll returns a Requirement satisfied only when every one of reqs
+// is satisfied.
+func RequireAll(reqs ...Requirement) Requirement {
+	return all(reqs)
+}
+
+func (a all) Caveats() []checkers.Caveat {
+	var out []checkers.Caveat
+	for _, r := range a {
+		out = append(out, r.Caveats()...)
+	}
+	return out
+}
+
+// anyOf is a Requirement satisfied by any one of a set of alternatives.
+//
+// A single macaroon's caveats are always combined with AND, so there is
+// no way to embed true OR semantics in one macaroon: satisfying any one
+// alternative fully discharges the set, but the macaroon still only
+// carries one alternative's caveats. RequireAny therefore uses the
+// first alternative that has any caveats at all; callers that need a
+// caller to be able to present either of two genuinely different
+// macaroons should mint one macaroon per alternative instead.
+type anyOf []Requirement
+
+// RequireAny returns a Requirement built from the first of reqs that
+// produces any caveats. See the anyOf type for the limitation this makes.
+func RequireAny(reqs ...Requirement) Requirement {
+	return anyOf(reqs)
+}
+
+func (a anyOf) Caveats() []checkers.Caveat {
+	for _, r := range a {
+		if c := r.Caveats(); len(c) > 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+// Policy binds an operation name, such as "read-grades", to the
+// Requirement a macaroon must satisfy before that operation is allowed.
+type Policy struct {
+	// Operation names the policy, for logging and lookup by route.
+	Operation string
+	// Requirement Caveats
+	Requirement Requirement
+}
+
+// New returns a Policy named operation, requiring req.
+func New(operation string, req Requirement) Policy {
+	return Policy{Operation: operation, Requirement: req}
+}
+
+// Caveats returns the caveats a macaroon minted to satisfy p must carry.
+func (p Policy) Caveats() []checkers.Caveat {
+	return p.Requirement.Caveats()
+}
+
+// checkerFunc adapts a plain function to the checkers.Checker interface.
+type checkerFunc struct {
+	cond string
+	fn   func(cond, arg string) error
+}
+
+func (c checkerFunc) Condition() string            { return c.cond }
+func (c checkerFunc) Check(cond, arg string) error { return c.fn(cond, arg) }
+
+// RequestChecker returns a checkers.Checker that enforces the
+// client-ip-prefix and http-method conditions produced by IPPrefix and
+// HTTPMethod against req. Since those conditions depend on the request
+// being served, unlike the stateless checkers in the checkers package,
+// a fresh RequestChecker must be built for each incoming request.
+func RequestChecker(req *http.Request) checkers.Checker {
+	return checkers.New(
+		checkerFunc{ipPrefixCond, func(_, arg string) error {
+			_, ipnet, err := net.ParseCIDR(arg)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			host, _, err := net.SplitHostPort(req.RemoteAddr)
+			if err != nil {
+				host = req.RemoteAddr
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !ipnet.Contains(ip) {
+				return errors.Errorf("client address %q not in %v", host, arg)
+			}
+			return nil
+		}},
+		checkerFunc{httpMethodCond, func(_, arg string) error {
+			if !strings.EqualFold(req.Method, arg) {
+				return errors.Errorf("method %v not allowed, want %v", req.Method, arg)
+			}
+			return nil
+		}},
+	)
+}
+
+// Attenuate returns a copy of m with each of reqs' caveats added as
+// first party caveats, for a client that wants to hand a more
+// restricted macaroon to a less trusted process than the one it holds.
+func Attenuate(m *macaroon.Macaroon, reqs ...Requirement) (*macaroon.Macaroon, error) {
+	m2 := m.Clone()
+	for _, r := range reqs {
+		for _, c := range r.Caveats() {
+			if c.Location != "" {
+				return nil, errors.New("cannot attenuate with a third party caveat")
+			}
+			if err := m2.AddFirstPartyCaveat(c.Condition); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+	}
+	return m2, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2016, Ales Stimec.
+
+package identity
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/juju/errors"
+)
+
+// HTTPGroupStore is a GroupStore that queries an external identity
+// manager over HTTP, following the identity-manager convention of
+// serving group membership at "/v1/u/<username>/groups".
+type HTTPGroupStore struct {
+	// BaseURL is the location of the identity manager, e.g.
+	// "http://localhost:8081".
+	BaseURL string
+	// Client is the HTTP client used to make requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// NewHTTPGroupStore returns a GroupStore that queries the identity
+// manager at baseURL.
+func NewHTTPGroupStore(baseURL string) *HTTPGroupStore {
+	return &HTTPGroupStore{BaseURL: baseURL}
+}
+
+// Groups implements GroupStore.Groups.
+func (s *HTTPGroupStore) Groups(username string) ([]string, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(s.BaseURL + "/v1/u/" + username + "/groups")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("cannot get groups for %q: %v", username, resp.Status)
+	}
+
+	var groups []string
+	if err := json.NewDecoder(resp.Body).Decode(&groups); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return groups, nil
+}
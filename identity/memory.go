@@ -0,0 +1,33 @@
+// Copyright 2016, Ales Stimec.
+
+package identity
+
+// MemoryUserStore is a UserStore that always resolves to a fixed
+// username, useful in tests.
+type MemoryUserStore string
+
+// NewMemoryUserStore returns a UserStore that always resolves to
+// username.
+func NewMemoryUserStore(username string) MemoryUserStore {
+	return MemoryUserStore(username)
+}
+
+// Username implements UserStore.Username.
+func (s MemoryUserStore) Username() (string, error) {
+	return string(s), nil
+}
+
+// MemoryGroupStore is a GroupStore backed by an in-memory map of
+// username to the groups it belongs to, useful in tests.
+type MemoryGroupStore map[string][]string
+
+// NewMemoryGroupStore returns a GroupStore backed by groups, a map of
+// username to the list of groups that username belongs to.
+func NewMemoryGroupStore(groups map[string][]string) MemoryGroupStore {
+	return MemoryGroupStore(groups)
+}
+
+// Groups implements GroupStore.Groups.
+func (s MemoryGroupStore) Groups(username string) ([]string, error) {
+	return s[username], nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2016, Ales Stimec.
+
+// Tests for the in-memory UserStore/GroupStore implementations added
+// alongside the pluggable identity package.
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/alesstimec/macaroon-demo/identity"
+)
+
+func TestMemoryUserStore(t *testing.T) {
+	store := identity.NewMemoryUserStore("alice")
+	got, err := store.Username()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "alice" {
+		t.Fatalf("got username %q, want %q", got, "alice")
+	}
+}
+
+func TestMemoryGroupStore(t *testing.T) {
+	store := identity.NewMemoryGroupStore(map[string][]string{
+		"alice": {"student"},
+	})
+
+	groups, err := store.Groups("alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 1 || groups[0] != "student" {
+		t.Fatalf("got groups %v, want [student]", groups)
+	}
+
+	groups, err = store.Groups("bob")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Fatalf("got groups %v, want none", groups)
+	}
+}
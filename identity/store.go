@@ -0,0 +1,20 @@
+// Copyright 2016, Ales Stimec.
+
+// Package identity provides the pluggable lookups used by the demo
+// services to resolve who a user is and which groups they belong to,
+// decoupling the handlers from any particular identity backend.
+package identity
+
+// UserStore resolves the identity of the user a service is acting on
+// behalf of.
+type UserStore interface {
+	// Username returns the canonical username for the service.
+	Username() (string, error)
+}
+
+// GroupStore resolves group membership for a username.
+type GroupStore interface {
+	// Groups returns the names of the groups the given username is a
+	// member of.
+	Groups(username string) ([]string, error)
+}
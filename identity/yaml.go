@@ -0,0 +1,78 @@
+// Copyright 2016, Ales Stimec.
+
+package identity
+
+import (
+	"io/ioutil"
+
+	"github.com/juju/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLUserStore is a UserStore backed by a user.yaml-style file
+// containing a single "username" field.
+type YAMLUserStore struct {
+	// Path is the path of the YAML file holding the username.
+	Path string
+}
+
+// NewYAMLUserStore returns a UserStore that reads the username from the
+// YAML file at path.
+func NewYAMLUserStore(path string) *YAMLUserStore {
+	return &YAMLUserStore{Path: path}
+}
+
+// Username implements UserStore.Username.
+func (s *YAMLUserStore) Username() (string, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	var userData struct {
+		Username string `yaml:"username"`
+	}
+	if err := yaml.Unmarshal(data, &userData); err != nil {
+		return "", errors.Trace(err)
+	}
+	return userData.Username, nil
+}
+
+// YAMLGroupStore is a GroupStore backed by a groups.yaml-style file
+// mapping group name to the usernames that belong to it.
+type YAMLGroupStore struct {
+	// Path is the path of the YAML file holding the groups.
+	Path string
+}
+
+// NewYAMLGroupStore returns a GroupStore that reads group membership from
+// the YAML file at path.
+func NewYAMLGroupStore(path string) *YAMLGroupStore {
+	return &YAMLGroupStore{Path: path}
+}
+
+// Groups implements GroupStore.Groups.
+func (s *YAMLGroupStore) Groups(username string) ([]string, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var groupData struct {
+		Groups map[string][]string `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(data, &groupData); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var groups []string
+	for group, members := range groupData.Groups {
+		for _, member := range members {
+			if member == username {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	return groups, nil
+}
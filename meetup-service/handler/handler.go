@@ -13,6 +13,8 @@ import (
 	"gopkg.in/macaroon-bakery.v1/bakery"
 	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
 	"gopkg.in/macaroon-bakery.v1/httpbakery"
+
+	"github.com/alesstimec/macaroon-demo/apierror"
 )
 
 const (
@@ -85,19 +87,19 @@ func (h *handler) checkStudent(w http.ResponseWriter, req *http.Request) (string
 		pathVars := mux.Vars(req)
 		username, ok := pathVars[usernamePath]
 		if !ok {
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			apierror.WriteError(w, errors.New("internal server error"))
 			return fail, errors.New("internal server error")
 		}
 		// compare the "path" username and the username declared in the macaroon.
 		if username != attrs[usernameCaveat] {
-			writeResponse(w, http.StatusForbidden, "forbidden")
+			apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 			return fail, errors.New("username mismatch")
 		}
 		return username, nil
 	}
 	// if the macaroon fails validation return an error.
 	if _, ok := errors.Cause(verr).(*bakery.VerificationError); !ok {
-		writeResponse(w, http.StatusForbidden, "forbidden")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 		return fail, errors.Trace(verr)
 	}
 	// mint a new macaroon
@@ -105,15 +107,15 @@ func (h *handler) checkStudent(w http.ResponseWriter, req *http.Request) (string
 		checkers.AllowCaveat("student"),
 		checkers.DenyCaveat("professor"),
 		checkers.NeedDeclaredCaveat(checkers.Caveat{h.config.UserServiceLocation, "is-user"}, usernameCaveat),
-		checkers.NeedDeclaredCaveat(checkers.Caveat{h.config.UniversityServiceLocation, "is-student"}, studentIDCaveat),
+		checkers.NeedDeclaredCaveat(checkers.Caveat{h.config.UniversityServiceLocation, "is-member-of student"}, studentIDCaveat),
 		checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
 	})
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, err)
+		apierror.WriteError(w, err)
 		return fail, errors.Annotate(err, "cannot mint a new macaroon")
 	}
 	// write the discharge required error in response.
-	httpbakery.WriteDischargeRequiredErrorForRequest(w, m, "/", verr, req)
+	apierror.WriteDischargeRequiredError(w, m, "/", verr, req)
 	return fail, errors.Trace(verr)
 }
 
@@ -127,34 +129,34 @@ func (h *handler) checkProfessor(w http.ResponseWriter, req *http.Request) (stri
 		pathVars := mux.Vars(req)
 		username, ok := pathVars[usernamePath]
 		if !ok {
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			apierror.WriteError(w, errors.New("internal server error"))
 			return fail, errors.New("internal server error")
 		}
 		// compare the "path" username and the username declared in the macaroon.
 		if username != attrs[usernameCaveat] {
-			writeResponse(w, http.StatusForbidden, "forbidden")
+			apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 			return fail, errors.New("username mismatch")
 		}
 		return username, nil
 	}
 	// if the macaroon fails validation return an error.
 	if _, ok := errors.Cause(verr).(*bakery.VerificationError); !ok {
-		writeResponse(w, http.StatusForbidden, "forbidden")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 		return fail, errors.Trace(verr)
 	}
 	// mint a new macaroon
 	m, err := h.config.Bakery.NewMacaroon("", nil, []checkers.Caveat{
 		checkers.AllowCaveat("student", "professor"),
 		checkers.NeedDeclaredCaveat(checkers.Caveat{h.config.UserServiceLocation, "is-user"}, usernameCaveat),
-		checkers.NeedDeclaredCaveat(checkers.Caveat{h.config.UniversityServiceLocation, "is-professor"}, professorIDCaveat),
+		checkers.NeedDeclaredCaveat(checkers.Caveat{h.config.UniversityServiceLocation, "is-member-of professor"}, professorIDCaveat),
 		checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
 	})
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, err)
+		apierror.WriteError(w, err)
 		return fail, errors.Annotate(err, "cannot mint a new macaroon")
 	}
 	// write the discharge required error in response.
-	httpbakery.WriteDischargeRequiredErrorForRequest(w, m, "/", verr, req)
+	apierror.WriteDischargeRequiredError(w, m, "/", verr, req)
 	return fail, errors.Trace(verr)
 }
 
@@ -0,0 +1,176 @@
+// Copyright 2016, Ales Stimec.
+
+package revocation
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+)
+
+// rootKeyLen is the length in bytes of a generated root key.
+const rootKeyLen = 24
+
+var (
+	rootKeyBucket    = []byte("rootkeys")
+	revocationBucket = []byte("revocations")
+)
+
+// BoltRootKeyStore is a RootKeyStore backed by a BoltDB database.
+type BoltRootKeyStore struct {
+	db *bolt.DB
+}
+
+// NewBoltRootKeyStore opens (creating if necessary) a BoltDB-backed
+// RootKeyStore at path.
+func NewBoltRootKeyStore(path string) (*BoltRootKeyStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(rootKeyBucket)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &BoltRootKeyStore{db: db}, nil
+}
+
+// Get implements RootKeyStore.Get.
+func (s *BoltRootKeyStore) Get(id string) ([]byte, error) {
+	var item []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(rootKeyBucket).Get([]byte(id)); v != nil {
+			item = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if item == nil {
+		return nil, errors.NotFoundf("root key for %q", id)
+	}
+	return item, nil
+}
+
+// RootKey implements RootKeyStore.RootKey.
+func (s *BoltRootKeyStore) RootKey() ([]byte, string, error) {
+	key := make([]byte, rootKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	id := utils.MustNewUUID().String()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(rootKeyBucket).Put([]byte(id), key)
+	})
+	if err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	return key, id, nil
+}
+
+// Close releases the underlying BoltDB database.
+func (s *BoltRootKeyStore) Close() error {
+	return s.db.Close()
+}
+
+// revocationEntry is the value stored for each revoked macaroon id.
+type revocationEntry struct {
+	ExpiresAt time.Time
+}
+
+// BoltList is a List backed by a BoltDB database.
+type BoltList struct {
+	db *bolt.DB
+}
+
+// NewBoltList opens (creating if necessary) a BoltDB-backed List at
+// path.
+func NewBoltList(path string) (*BoltList, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(revocationBucket)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &BoltList{db: db}, nil
+}
+
+// Revoke implements List.Revoke.
+func (l *BoltList) Revoke(id string, expiresAt time.Time) error {
+	data, err := json.Marshal(revocationEntry{ExpiresAt: expiresAt})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return l.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(revocationBucket).Put([]byte(id), data)
+	})
+}
+
+// IsRevoked implements List.IsRevoked.
+func (l *BoltList) IsRevoked(id string) (bool, error) {
+	var data []byte
+	err := l.db.View(func(tx *bolt.Tx) error {
+		data = tx.Bucket(revocationBucket).Get([]byte(id))
+		return nil
+	})
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if data == nil {
+		return false, nil
+	}
+	var entry revocationEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return false, errors.Trace(err)
+	}
+	if entry.ExpiresAt.Before(time.Now()) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Sweep implements List.Sweep.
+func (l *BoltList) Sweep() error {
+	now := time.Now()
+	return l.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(revocationBucket)
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var entry revocationEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return errors.Trace(err)
+			}
+			if entry.ExpiresAt.Before(now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close releases the underlying BoltDB database.
+func (l *BoltList) Close() error {
+	return l.db.Close()
+}
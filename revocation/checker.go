@@ -0,0 +1,43 @@
+// Copyright 2016, Ales Stimec.
+
+package revocation
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
+)
+
+// Condition is the first party caveat condition that declares the id of
+// the macaroon it appears in, allowing revocation to be checked during
+// httpbakery.CheckRequest.
+const Condition = "root-key-id"
+
+// checker is a checkers.Checker that fails the Condition caveat if its
+// declared macaroon id has been revoked.
+type checker struct {
+	list List
+}
+
+// Checker returns a checkers.Checker for Condition that consults list on
+// every check, so a revoked macaroon is rejected even while its
+// TimeBefore caveat is still valid.
+func Checker(list List) checkers.Checker {
+	return checker{list: list}
+}
+
+// Condition implements checkers.Checker.Condition.
+func (c checker) Condition() string {
+	return Condition
+}
+
+// Check implements checkers.Checker.Check.
+func (c checker) Check(_, arg string) error {
+	revoked, err := c.list.IsRevoked(arg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if revoked {
+		return errors.Errorf("macaroon %q has been revoked", arg)
+	}
+	return nil
+}
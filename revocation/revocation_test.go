@@ -0,0 +1,151 @@
+// Copyright 2016, Ales Stimec.
+
+package revocation_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/macaroon-bakery.v1/bakery"
+	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/alesstimec/macaroon-demo/revocation"
+)
+
+// TestCheckerRejectsRevokedId demonstrates that a macaroon id rejected
+// by revocation.Checker stops passing checks as soon as it is revoked,
+// even though the revocation expiry is far beyond the macaroon's own
+// TimeBefore caveat - i.e. a leaked macaroon stops working immediately,
+// rather than having to wait out its natural expiry.
+func TestCheckerRejectsRevokedId(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revocation-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list, err := revocation.NewBoltList(filepath.Join(dir, "revocations.db"))
+	if err != nil {
+		t.Fatalf("cannot create revocation list: %v", err)
+	}
+	defer list.Close()
+
+	checker := revocation.Checker(list)
+	const id = "leaked-macaroon-id"
+
+	if err := checker.Check(checker.Condition(), id); err != nil {
+		t.Fatalf("unexpected error before revocation: %v", err)
+	}
+
+	// Revoke the id with an expiry well beyond the macaroon's own
+	// TimeBefore caveat, simulating an operator reacting to a leak.
+	if err := list.Revoke(id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("cannot revoke id: %v", err)
+	}
+
+	if err := checker.Check(checker.Condition(), id); err == nil {
+		t.Fatal("expected an error checking a revoked id, got nil")
+	}
+}
+
+// TestBoltRootKeyStoreRoundTrip exercises the bakery.RootKeyStorage
+// shape the bolt-backed store implements.
+func TestBoltRootKeyStoreRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revocation-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := revocation.NewBoltRootKeyStore(filepath.Join(dir, "rootkeys.db"))
+	if err != nil {
+		t.Fatalf("cannot create root key store: %v", err)
+	}
+	defer store.Close()
+
+	key, id, err := store.RootKey()
+	if err != nil {
+		t.Fatalf("cannot create root key: %v", err)
+	}
+	if id == "" {
+		t.Fatal("expected a non-empty id")
+	}
+
+	got, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("cannot get root key: %v", err)
+	}
+	if string(got) != string(key) {
+		t.Fatalf("got root key %x, want %x", got, key)
+	}
+}
+
+// TestLeakedMacaroonStopsWorkingAfterRevocation is an end-to-end test of
+// the mint/revoke/recheck cycle against a bakery.Service configured with
+// a RootKeyStore exactly like target-service's: it mints a macaroon the
+// same way target-service's checkUser does (an empty id, the real id
+// only available from m.Id() afterwards, with the revocation caveat
+// added once that id is known), then checks that the bakery accepts the
+// macaroon, revokes it, and checks that the bakery now rejects it even
+// though its TimeBefore caveat has not expired. This exercises
+// bakery.Service.NewMacaroon together with a configured RootKeyStore,
+// which is exactly where the "non-empty id" minting bug lived.
+func TestLeakedMacaroonStopsWorkingAfterRevocation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "revocation-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootKeyStore, err := revocation.NewBoltRootKeyStore(filepath.Join(dir, "rootkeys.db"))
+	if err != nil {
+		t.Fatalf("cannot create root key store: %v", err)
+	}
+	defer rootKeyStore.Close()
+
+	revocationList, err := revocation.NewBoltList(filepath.Join(dir, "revocations.db"))
+	if err != nil {
+		t.Fatalf("cannot create revocation list: %v", err)
+	}
+	defer revocationList.Close()
+
+	key, err := bakery.GenerateKey()
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	svc, err := bakery.NewService(bakery.NewServiceParams{
+		Location:     "target-service",
+		Key:          key,
+		RootKeyStore: rootKeyStore,
+	})
+	if err != nil {
+		t.Fatalf("cannot create bakery service: %v", err)
+	}
+
+	m, err := svc.NewMacaroon("", nil, []checkers.Caveat{
+		checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
+	})
+	if err != nil {
+		t.Fatalf("cannot mint macaroon: %v", err)
+	}
+	if err := m.AddFirstPartyCaveat(revocation.Condition + " " + m.Id()); err != nil {
+		t.Fatalf("cannot add revocation caveat: %v", err)
+	}
+
+	checker := checkers.New(checkers.TimeBefore, revocation.Checker(revocationList))
+	if err := svc.Check(macaroon.Slice{m}, checker); err != nil {
+		t.Fatalf("unexpected error checking macaroon before revocation: %v", err)
+	}
+
+	if err := revocationList.Revoke(m.Id(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("cannot revoke macaroon: %v", err)
+	}
+
+	if err := svc.Check(macaroon.Slice{m}, checker); err == nil {
+		t.Fatal("expected an error checking a revoked macaroon, got nil")
+	}
+}
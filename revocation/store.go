@@ -0,0 +1,30 @@
+// Copyright 2016, Ales Stimec.
+
+// Package revocation provides persistent root key storage and a
+// revocation list for macaroons minted by a bakery.Service, so a leaked
+// macaroon can be invalidated before its TimeBefore caveat expires.
+package revocation
+
+import "time"
+
+// RootKeyStore persists the root key for each minted macaroon. Its
+// shape mirrors bakery.RootKeyStorage, so a RootKeyStore can be used
+// directly as the RootKeyStore field of bakery.NewServiceParams.
+type RootKeyStore interface {
+	// Get returns the root key stored under id.
+	Get(id string) ([]byte, error)
+	// RootKey returns a new root key to use for a macaroon being
+	// minted, along with the id it has been stored under.
+	RootKey() ([]byte, string, error)
+}
+
+// List records macaroon ids that have been revoked ahead of their
+// natural expiry.
+type List interface {
+	// Revoke marks id as revoked until expiresAt.
+	Revoke(id string, expiresAt time.Time) error
+	// IsRevoked reports whether id is currently revoked.
+	IsRevoked(id string) (bool, error)
+	// Sweep removes revocation entries whose expiry has passed.
+	Sweep() error
+}
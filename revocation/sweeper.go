@@ -0,0 +1,47 @@
+// Copyright 2016, Ales Stimec.
+
+package revocation
+
+import (
+	"time"
+)
+
+// Sweeper periodically removes expired entries from a List so it does
+// not grow without bound.
+type Sweeper struct {
+	list   List
+	ticker *time.Ticker
+	stop   chan struct{}
+}
+
+// NewSweeper returns a Sweeper that calls list.Sweep every interval. Call
+// Start to begin sweeping in the background.
+func NewSweeper(list List, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		list:   list,
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start runs the sweep loop in a new goroutine until Stop is called.
+func (s *Sweeper) Start() {
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				// Best effort: a failed sweep just means stale entries
+				// stick around until the next tick.
+				s.list.Sweep()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the sweep loop.
+func (s *Sweeper) Stop() {
+	s.ticker.Stop()
+	close(s.stop)
+}
@@ -0,0 +1,155 @@
+// Copyright 2016, Ales Stimec.
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"gopkg.in/macaroon-bakery.v1/bakery"
+	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
+	"gopkg.in/macaroon-bakery.v1/httpbakery"
+
+	"github.com/alesstimec/macaroon-demo/apierror"
+	"github.com/alesstimec/macaroon-demo/caveats"
+)
+
+const (
+	usernamePath   = "username"
+	usernameCaveat = "username"
+	studentIDGroup = "student-id"
+	professorGroup = "professor-id"
+
+	readGradesOp = "read-grades"
+	postGradesOp = "post-grades"
+)
+
+// HandlerConfig contains configuration for the handler.
+type HandlerConfig struct {
+	// Bakery is the macaroon bakery to be used by the handler.
+	Bakery *bakery.Service
+	// UserServiceLocation is the location of the user service.
+	UserServiceLocation string
+	// UniversityServiceLocation is the location of the university service.
+	UniversityServiceLocation string
+}
+
+// NewHandler returns a new handler struct using the provided config. The
+// policies it enforces are declared once here; adding a new endpoint
+// that needs a new combination of caveats only requires a new policy
+// declaration, not a new checkStudent/checkProfessor-style function.
+func NewHandler(config HandlerConfig) *handler {
+	return &handler{
+		config: config,
+		policies: map[string]caveats.Policy{
+			readGradesOp: caveats.New(readGradesOp, caveats.RequireAll(
+				caveats.ThirdParty(config.UserServiceLocation, "is-user", usernameCaveat),
+				caveats.ThirdParty(config.UniversityServiceLocation, "is-member-of student", studentIDGroup),
+				caveats.TimeBefore(5*time.Minute),
+			)),
+			postGradesOp: caveats.New(postGradesOp, caveats.RequireAll(
+				caveats.ThirdParty(config.UserServiceLocation, "is-user", usernameCaveat),
+				caveats.ThirdParty(config.UniversityServiceLocation, "is-member-of professor", professorGroup),
+				caveats.TimeBefore(5*time.Minute),
+			)),
+		},
+	}
+}
+
+type handler struct {
+	config HandlerConfig
+	// policies maps an operation name to the Policy that protects it.
+	policies map[string]caveats.Policy
+}
+
+// RegisterHandlers registers all endpoints served by the handler.
+func (h *handler) RegisterHandlers(r *mux.Router) {
+	r.HandleFunc("/grades/{username}", h.readGrades).Methods("GET")
+	r.HandleFunc("/grades/{username}", h.postGrades).Methods("POST")
+}
+
+func (h *handler) readGrades(w http.ResponseWriter, req *http.Request) {
+	username, err := h.checkPolicy(readGradesOp, w, req)
+	if err != nil {
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: fmt.Sprintf("grades for %v", username),
+	}
+	writeResponse(w, http.StatusOK, response)
+}
+
+func (h *handler) postGrades(w http.ResponseWriter, req *http.Request) {
+	username, err := h.checkPolicy(postGradesOp, w, req)
+	if err != nil {
+		return
+	}
+
+	response := struct {
+		Message string `json:"message"`
+	}{
+		Message: fmt.Sprintf("grades for %v updated by %v", mux.Vars(req)[usernamePath], username),
+	}
+	writeResponse(w, http.StatusOK, response)
+}
+
+// checkPolicy verifies the macaroons presented with req against the
+// named policy, minting a new macaroon carrying the policy's caveats if
+// none are presented yet. On success it returns the username declared
+// by the caller's macaroon, after checking it matches the "username"
+// path variable.
+func (h *handler) checkPolicy(operation string, w http.ResponseWriter, req *http.Request) (string, error) {
+	fail := ""
+	policy, ok := h.policies[operation]
+	if !ok {
+		err := errors.Errorf("no policy declared for operation %q", operation)
+		apierror.WriteError(w, err)
+		return fail, err
+	}
+
+	attrs, verr := httpbakery.CheckRequest(h.config.Bakery, req, nil, checkers.New(checkers.TimeBefore, caveats.RequestChecker(req)))
+	if verr == nil {
+		pathVars := mux.Vars(req)
+		username, ok := pathVars[usernamePath]
+		if !ok {
+			apierror.WriteError(w, errors.New("internal server error"))
+			return fail, errors.New("internal server error")
+		}
+		if username != attrs[usernameCaveat] {
+			apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
+			return fail, errors.New("username mismatch")
+		}
+		return attrs[usernameCaveat], nil
+	}
+	// if the macaroon fails validation return an error.
+	if _, ok := errors.Cause(verr).(*bakery.VerificationError); !ok {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
+		return fail, errors.Trace(verr)
+	}
+	// mint a new macaroon carrying the caveats the policy requires.
+	m, err := h.config.Bakery.NewMacaroon("", nil, policy.Caveats())
+	if err != nil {
+		apierror.WriteError(w, err)
+		return fail, errors.Annotate(err, "cannot mint a new macaroon")
+	}
+	// write the discharge required error in response.
+	apierror.WriteDischargeRequiredError(w, m, "/", verr, req)
+	return fail, errors.Trace(verr)
+}
+
+func writeResponse(w http.ResponseWriter, code int, object interface{}) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
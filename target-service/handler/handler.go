@@ -3,6 +3,7 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,11 +14,17 @@ import (
 	"gopkg.in/macaroon-bakery.v1/bakery"
 	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
 	"gopkg.in/macaroon-bakery.v1/httpbakery"
+
+	"github.com/alesstimec/macaroon-demo/apierror"
+	"github.com/alesstimec/macaroon-demo/revocation"
 )
 
 const (
 	usernamePath   = "username"
 	usernameCaveat = "username"
+	// adminSecretHeader carries the shared secret required to call the
+	// /revoke admin endpoint.
+	adminSecretHeader = "X-Admin-Secret"
 )
 
 // HandlerConfig contains configuration for the handler.
@@ -26,6 +33,12 @@ type HandlerConfig struct {
 	Bakery *bakery.Service
 	// UserServiceLocation is the location of the user service.
 	UserServiceLocation string
+	// RevocationList tracks macaroon ids that have been revoked ahead of
+	// their natural expiry.
+	RevocationList revocation.List
+	// AdminSecret is the shared secret that must be presented in the
+	// X-Admin-Secret header to call the /revoke admin endpoint.
+	AdminSecret string
 }
 
 // NewHandler returns a new handler struct using the provided condig.
@@ -40,6 +53,32 @@ type handler struct {
 // RegisterHandlers registers all endpoints served by the handler.
 func (h *handler) RegisterHandlers(r *mux.Router) {
 	r.HandleFunc("/{username}", h.helloUser).Methods("GET")
+	r.HandleFunc("/revoke", h.revoke).Methods("POST")
+}
+
+// revoke is an admin endpoint that revokes a minted macaroon by id,
+// ahead of its natural expiry.
+func (h *handler) revoke(w http.ResponseWriter, req *http.Request) {
+	secret := req.Header.Get(adminSecretHeader)
+	if h.config.AdminSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.config.AdminSecret)) != 1 {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
+		return
+	}
+
+	var body struct {
+		Id string `json:"id"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Id == "" {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
+		return
+	}
+
+	err := h.config.RevocationList.Revoke(body.Id, time.Now().Add(5*time.Minute))
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, "revoked")
 }
 
 func (h *handler) helloUser(w http.ResponseWriter, req *http.Request) {
@@ -58,38 +97,46 @@ func (h *handler) helloUser(w http.ResponseWriter, req *http.Request) {
 
 func (h *handler) checkUser(w http.ResponseWriter, req *http.Request) (string, error) {
 	fail := ""
-	attrs, verr := httpbakery.CheckRequest(h.config.Bakery, req, nil, checkers.TimeBefore)
+	attrs, verr := httpbakery.CheckRequest(h.config.Bakery, req, nil, checkers.New(checkers.TimeBefore, revocation.Checker(h.config.RevocationList)))
 	if verr == nil {
 		// get the username from the url path.
 		pathVars := mux.Vars(req)
 		username, ok := pathVars[usernamePath]
 		if !ok {
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			apierror.WriteError(w, errors.New("internal server error"))
 			return fail, errors.New("internal server error")
 		}
 		// compare the "path" username and the username declared in the macaroon.
 		if username != attrs[usernameCaveat] {
-			writeResponse(w, http.StatusForbidden, "forbidden")
+			apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 			return fail, errors.New("username mismatch")
 		}
 		return username, nil
 	}
 	// if the macaroon fails validation return an error.
 	if _, ok := errors.Cause(verr).(*bakery.VerificationError); !ok {
-		writeResponse(w, http.StatusForbidden, "forbidden")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 		return fail, errors.Trace(verr)
 	}
-	// mint a new macaroon
+	// the bakery is configured with a RootKeyStore, which requires
+	// NewMacaroon to be called with an empty id and root key; the real
+	// id is only known afterwards, via m.Id().
 	m, err := h.config.Bakery.NewMacaroon("", nil, []checkers.Caveat{
 		checkers.NeedDeclaredCaveat(checkers.Caveat{h.config.UserServiceLocation, "is-user"}, usernameCaveat),
 		checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
 	})
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, err)
+		apierror.WriteError(w, err)
 		return fail, errors.Annotate(err, "cannot mint a new macaroon")
 	}
+	// now that the macaroon has its real id, add the revocation caveat
+	// so it can later be looked up for revocation.
+	if err := m.AddFirstPartyCaveat(revocation.Condition + " " + m.Id()); err != nil {
+		apierror.WriteError(w, err)
+		return fail, errors.Annotate(err, "cannot add revocation caveat")
+	}
 	// write the discharge required error in response.
-	httpbakery.WriteDischargeRequiredErrorForRequest(w, m, "/", verr, req)
+	apierror.WriteDischargeRequiredError(w, m, "/", verr, req)
 	return fail, errors.Trace(verr)
 }
 
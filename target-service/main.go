@@ -6,16 +6,24 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"gopkg.in/macaroon-bakery.v1/bakery"
 	"gopkg.in/macaroon-bakery.v1/httpbakery"
 
+	"github.com/alesstimec/macaroon-demo/revocation"
 	"github.com/alesstimec/macaroon-demo/target-service/handler"
 )
 
 const (
 	userServiceLocation = "http://localhost:9080"
+	rootKeyStoreFile    = "rootkeys.db"
+	revocationListFile  = "revocations.db"
+	sweepInterval       = time.Minute
+	// adminSecretEnvVar names the environment variable holding the
+	// shared secret required to call the /revoke admin endpoint.
+	adminSecretEnvVar = "TARGET_SERVICE_ADMIN_SECRET"
 )
 
 func returnError(err error) {
@@ -42,19 +50,42 @@ func main() {
 	}
 	fmt.Println("keyring created")
 
+	rootKeyStore, err := revocation.NewBoltRootKeyStore(rootKeyStoreFile)
+	if err != nil {
+		returnError(err)
+	}
+	fmt.Println("root key store created")
+
+	revocationList, err := revocation.NewBoltList(revocationListFile)
+	if err != nil {
+		returnError(err)
+	}
+	fmt.Println("revocation list created")
+
+	sweeper := revocation.NewSweeper(revocationList, sweepInterval)
+	sweeper.Start()
+
 	svc, err := bakery.NewService(bakery.NewServiceParams{
-		Location: "target-service",
-		Key:      keypair,
-		Locator:  keyring,
+		Location:     "target-service",
+		Key:          keypair,
+		Locator:      keyring,
+		RootKeyStore: rootKeyStore,
 	})
 	if err != nil {
 		returnError(err)
 	}
 	fmt.Println("bakery created")
 
+	adminSecret := os.Getenv(adminSecretEnvVar)
+	if adminSecret == "" {
+		returnError(fmt.Errorf("%s must be set to a shared secret for the /revoke admin endpoint", adminSecretEnvVar))
+	}
+
 	h := handler.NewHandler(handler.HandlerConfig{
 		Bakery:              svc,
 		UserServiceLocation: userServiceLocation,
+		RevocationList:      revocationList,
+		AdminSecret:         adminSecret,
 	})
 	fmt.Println("handler created")
 
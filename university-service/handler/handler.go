@@ -17,13 +17,15 @@ import (
 	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
 	"gopkg.in/macaroon-bakery.v1/httpbakery"
 	"gopkg.in/macaroon.v1"
-	"gopkg.in/yaml.v2"
+
+	"github.com/alesstimec/macaroon-demo/apierror"
+	"github.com/alesstimec/macaroon-demo/identity"
 )
 
 const (
 	usernamePath   = "username"
 	usernameCaveat = "username"
-	groupsFile     = "groups.yaml"
+	isMemberOfCond = "is-member-of"
 )
 
 // HandlerConfig contains configuration for the handler.
@@ -32,6 +34,8 @@ type HandlerConfig struct {
 	Bakery *bakery.Service
 	// UserServiceLocation is the location of the user service.
 	UserServiceLocation string
+	// GroupStore resolves which groups a username belongs to.
+	GroupStore identity.GroupStore
 }
 
 // NewHandler returns a new handler struct using the provided config.
@@ -69,17 +73,17 @@ func (h *handler) discharge(w http.ResponseWriter, req *http.Request) {
 
 	data, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		writeResponse(w, http.StatusBadRequest, "bad request")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
 		return
 	}
 	values, err := url.ParseQuery(string(data))
 	if err != nil {
-		writeResponse(w, http.StatusBadRequest, "bad request")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
 		return
 	}
 	id := values.Get("id")
 	if id == "" {
-		writeResponse(w, http.StatusBadRequest, "bad request")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
 		return
 	}
 
@@ -90,17 +94,7 @@ func (h *handler) discharge(w http.ResponseWriter, req *http.Request) {
 			}),
 		id)
 	if err != nil {
-		e := struct {
-			Message string
-			Code    string
-		}{
-			Message: err.Error(),
-			Code:    "unauthorized",
-		}
-		writeResponse(w,
-			http.StatusUnauthorized,
-			e,
-		)
+		apierror.WriteError(w, apierror.Newf(apierror.CodeUnauthorized, "%v", err))
 		return
 	}
 
@@ -114,49 +108,35 @@ func (h *handler) discharge(w http.ResponseWriter, req *http.Request) {
 
 // checkThirdPartyCaveat checks the the third party caveat and returns a declared caveat
 // declaring the username.
+//
+// Conditions are of the form "is-member-of <group>", so new roles can be
+// added by simply adding a group to the GroupStore, without any code
+// changes here.
 func (h *handler) checkThirdPartyCaveat(username, cavId, cav string) ([]checkers.Caveat, error) {
-	cond, _, err := checkers.ParseCaveat(cav)
+	cond, arg, err := checkers.ParseCaveat(cav)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if cond != isMemberOfCond {
+		return nil, checkers.ErrCaveatNotRecognized
+	}
+	if arg == "" {
+		return nil, errors.New("no group specified")
+	}
 
-	groups, err := readGroups()
+	groups, err := h.config.GroupStore.Groups(username)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-
-	switch cond {
-	case "is-student":
-		students, ok := groups["student"]
-		if !ok {
-			return nil, errors.New("student group not found")
-		}
-		for _, student := range students {
-			if student == username {
-				return []checkers.Caveat{
-					checkers.DeclaredCaveat("student-id", utils.MustNewUUID().String()),
-					checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
-				}, nil
-			}
-		}
-		return nil, errors.New("not a student")
-	case "is-professor":
-		students, ok := groups["professor"]
-		if !ok {
-			return nil, errors.New("professor group not found")
+	for _, group := range groups {
+		if group == arg {
+			return []checkers.Caveat{
+				checkers.DeclaredCaveat(arg+"-id", utils.MustNewUUID().String()),
+				checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
+			}, nil
 		}
-		for _, student := range students {
-			if student == username {
-				return []checkers.Caveat{
-					checkers.DeclaredCaveat("professor-id", utils.MustNewUUID().String()),
-					checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
-				}, nil
-			}
-		}
-		return nil, errors.New("not a professor")
-	default:
-		return nil, checkers.ErrCaveatNotRecognized
 	}
+	return nil, errors.Errorf("not a member of %q", arg)
 }
 
 // writeResponse writes the http response.
@@ -171,23 +151,6 @@ func writeResponse(w http.ResponseWriter, code int, object interface{}) {
 	w.Write(data)
 }
 
-// readGroups reads groups from groups.yaml.
-func readGroups() (map[string][]string, error) {
-	data, err := ioutil.ReadFile(groupsFile)
-	if err != nil {
-		return nil, err
-	}
-
-	var groupData struct {
-		Groups map[string][]string `yaml:"groups"`
-	}
-	err = yaml.Unmarshal(data, &groupData)
-	if err != nil {
-		return nil, err
-	}
-	return groupData.Groups, nil
-}
-
 func (h *handler) checkUser(w http.ResponseWriter, req *http.Request) (string, error) {
 	fail := ""
 	// we check the presented macaroons
@@ -196,14 +159,14 @@ func (h *handler) checkUser(w http.ResponseWriter, req *http.Request) (string, e
 	if verr == nil {
 		username, ok := attrs[usernameCaveat]
 		if !ok {
-			writeResponse(w, http.StatusForbidden, "forbidden")
+			apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 			return fail, errors.New("username not declared")
 		}
 		return username, nil
 	}
 	// if the macaroon fails validation return an error.
 	if _, ok := errors.Cause(verr).(*bakery.VerificationError); !ok {
-		writeResponse(w, http.StatusForbidden, "forbidden")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
 		return fail, errors.Trace(verr)
 	}
 	// mint a new macaroon
@@ -212,10 +175,10 @@ func (h *handler) checkUser(w http.ResponseWriter, req *http.Request) (string, e
 		checkers.TimeBeforeCaveat(time.Now().Add(5 * time.Minute)),
 	})
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, err)
+		apierror.WriteError(w, err)
 		return fail, errors.Annotate(err, "cannot mint a new macaroon")
 	}
 	// write the discharge required error in response.
-	httpbakery.WriteDischargeRequiredErrorForRequest(w, m, "/", verr, req)
+	apierror.WriteDischargeRequiredError(w, m, "/", verr, req)
 	return fail, errors.Trace(verr)
 }
@@ -0,0 +1,85 @@
+// Copyright 2016, Ales Stimec.
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gopkg.in/macaroon-bakery.v1/bakery"
+	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
+	"gopkg.in/macaroon-bakery.v1/httpbakery"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/alesstimec/macaroon-demo/apierror"
+)
+
+// agentMacaroonExpiry is how long an agent login macaroon remains valid.
+// It is much longer than the 5 minute expiry used elsewhere, since an
+// agent is expected to keep reusing the same macaroon without any
+// interactive step.
+const agentMacaroonExpiry = 30 * 24 * time.Hour
+
+// agentLoginRequest is the body expected by the /agent/login endpoint.
+type agentLoginRequest struct {
+	Username  string            `json:"username"`
+	PublicKey *bakery.PublicKey `json:"public_key"`
+}
+
+// agentLogin mints a macaroon for a headless client, carrying a local
+// third party caveat addressed to the client's own public key. The
+// client discharges that caveat itself by proving possession of the
+// matching private key, so no interactive /visit step is required.
+func (h *handler) agentLogin(w http.ResponseWriter, req *http.Request) {
+	var loginReq agentLoginRequest
+	if err := json.NewDecoder(req.Body).Decode(&loginReq); err != nil {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
+		return
+	}
+	if loginReq.Username == "" || loginReq.PublicKey == nil {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
+		return
+	}
+
+	configuredUsername, err := h.config.UserStore.Username()
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
+	if loginReq.Username != configuredUsername {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
+		return
+	}
+
+	m, err := h.config.Bakery.NewMacaroon("", nil, []checkers.Caveat{
+		checkers.DeclaredCaveat(usernameCaveat, loginReq.Username),
+		bakery.LocalThirdPartyCaveat(loginReq.PublicKey),
+		checkers.TimeBeforeCaveat(time.Now().Add(agentMacaroonExpiry)),
+	})
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
+
+	response := struct {
+		Macaroon *macaroon.Macaroon
+	}{
+		Macaroon: m,
+	}
+	writeResponse(w, http.StatusOK, response)
+}
+
+// authenticatedUsername returns the username for the caller of /discharge,
+// either from an already-discharged agent macaroon presented with the
+// request, or from an interactive login session. The second result is
+// false if neither is present, meaning an interactive login is required.
+func (h *handler) authenticatedUsername(req *http.Request) (string, bool) {
+	attrs, err := httpbakery.CheckRequest(h.config.Bakery, req, nil, checkers.TimeBefore)
+	if err == nil {
+		if username, ok := attrs[usernameCaveat]; ok {
+			return username, true
+		}
+	}
+	return sessionUsername(req)
+}
@@ -8,38 +8,52 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
 
 	"github.com/gorilla/mux"
 	"gopkg.in/macaroon-bakery.v1/bakery"
 	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
 	"gopkg.in/macaroon.v1"
-	"gopkg.in/yaml.v2"
+
+	"github.com/alesstimec/macaroon-demo/apierror"
+	"github.com/alesstimec/macaroon-demo/identity"
 )
 
 const (
 	usernameCaveat = "username"
-	usernameFile   = "user.yaml"
 )
 
 // HandlerConfig contains configuration for the handler.
 type HandlerConfig struct {
 	// Bakery is the macaroon bakery to be used by the handler.
 	Bakery *bakery.Service
+	// UserStore resolves the username of the user this service acts on
+	// behalf of.
+	UserStore identity.UserStore
 }
 
 // NewHandler returns a new handler struct using the provided config.
 func NewHandler(config HandlerConfig) *handler {
-	return &handler{config: config}
+	return &handler{
+		config: config,
+		logins: make(map[string]*pendingLogin),
+	}
 }
 
 type handler struct {
 	config HandlerConfig
+
+	mu     sync.Mutex
+	logins map[string]*pendingLogin
 }
 
 // RegisterHandlers registers all endpoints served by the handler.
 func (h *handler) RegisterHandlers(r *mux.Router) {
 	r.HandleFunc("/publickey", h.publicKey).Methods("GET")
 	r.HandleFunc("/discharge", h.discharge).Methods("POST")
+	r.HandleFunc("/visit", h.visit).Methods("GET", "POST")
+	r.HandleFunc("/wait", h.wait).Methods("GET")
+	r.HandleFunc("/agent/login", h.agentLogin).Methods("POST")
 }
 
 // publicKey returns the bakery service public key.
@@ -57,28 +71,34 @@ func (h *handler) publicKey(w http.ResponseWriter, req *http.Request) {
 func (h *handler) discharge(w http.ResponseWriter, req *http.Request) {
 	data, err := ioutil.ReadAll(req.Body)
 	if err != nil {
-		writeResponse(w, http.StatusBadRequest, "bad request")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
 		return
 	}
 	values, err := url.ParseQuery(string(data))
 	if err != nil {
-		writeResponse(w, http.StatusBadRequest, "bad request")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
 		return
 	}
 	id := values.Get("id")
 	if id == "" {
-		writeResponse(w, http.StatusBadRequest, "bad request")
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
+		return
+	}
+
+	username, ok := h.authenticatedUsername(req)
+	if !ok {
+		h.requireInteraction(w, req, id)
 		return
 	}
 
 	m, err := h.config.Bakery.Discharge(
 		bakery.ThirdPartyCheckerFunc(
 			func(cavId, cav string) ([]checkers.Caveat, error) {
-				return h.checkThirdPartyCaveat(req, cavId, cav)
+				return h.checkThirdPartyCaveat(username, cavId, cav)
 			}),
 		id)
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, "internal server error")
+		apierror.WriteError(w, err)
 		return
 	}
 
@@ -92,7 +112,7 @@ func (h *handler) discharge(w http.ResponseWriter, req *http.Request) {
 
 // checkThirdPartyCaveat checks the the third party caveat and returns a declared caveat
 // declaring the username.
-func (h *handler) checkThirdPartyCaveat(req *http.Request, cavId, cav string) ([]checkers.Caveat, error) {
+func (h *handler) checkThirdPartyCaveat(username string, cavId, cav string) ([]checkers.Caveat, error) {
 	cond, _, err := checkers.ParseCaveat(cav)
 	if err != nil {
 		return nil, err
@@ -101,7 +121,6 @@ func (h *handler) checkThirdPartyCaveat(req *http.Request, cavId, cav string) ([
 	// addressed to it.
 	switch cond {
 	case "is-user":
-		username := readUsername()
 		// we are returning a declared caveat, which means that the "username" will
 		// be returned to the target service when calling the httpbakery.CheckRequest method.
 		return []checkers.Caveat{checkers.DeclaredCaveat(usernameCaveat, username)}, nil
@@ -121,22 +140,3 @@ func writeResponse(w http.ResponseWriter, code int, object interface{}) {
 	w.WriteHeader(code)
 	w.Write(data)
 }
-
-// readUsername reds the username from user.yaml.
-func readUsername() string {
-	username := "bob"
-
-	data, err := ioutil.ReadFile(usernameFile)
-	if err != nil {
-		return username
-	}
-
-	var userData struct {
-		Username string `yaml:"username"`
-	}
-	err = yaml.Unmarshal(data, &userData)
-	if err != nil {
-		return username
-	}
-	return userData.Username
-}
@@ -0,0 +1,167 @@
+// Copyright 2016, Ales Stimec.
+
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/juju/utils"
+	"gopkg.in/macaroon-bakery.v1/bakery"
+	"gopkg.in/macaroon-bakery.v1/bakery/checkers"
+	"gopkg.in/macaroon-bakery.v1/httpbakery"
+	"gopkg.in/macaroon.v1"
+
+	"github.com/alesstimec/macaroon-demo/apierror"
+)
+
+const (
+	// sessionCookieName is the cookie set in the user's browser once they
+	// have completed the /visit login form.
+	sessionCookieName = "macaroon-demo-session"
+	// waitIdParam is the query parameter used to correlate /visit and
+	// /wait requests with the discharge that is waiting on them.
+	waitIdParam = "waitid"
+	// waitTimeout bounds how long /wait will block for a login to complete.
+	waitTimeout = 5 * time.Minute
+)
+
+// pendingLogin tracks an interactive login that is waiting for the user
+// to submit credentials through the /visit form.
+type pendingLogin struct {
+	// dischargeId is the discharge macaroon id that triggered this login.
+	dischargeId string
+	// done is closed once the login form has been submitted.
+	done chan struct{}
+	// username is set once the login form has been submitted.
+	username string
+}
+
+var visitPage = template.Must(template.New("visit").Parse(`<!DOCTYPE html>
+<html>
+<head><title>macaroon-demo login</title></head>
+<body>
+<h1>Log in</h1>
+<form method="POST" action="/visit?waitid={{.WaitId}}">
+<label>Username: <input type="text" name="username"></label>
+<input type="submit" value="Log in">
+</form>
+</body>
+</html>
+`))
+
+// requireInteraction registers a pending login for the discharge identified
+// by dischargeId and writes an interaction-required error pointing the
+// client at the /visit and /wait endpoints.
+func (h *handler) requireInteraction(w http.ResponseWriter, req *http.Request, dischargeId string) {
+	waitId := utils.MustNewUUID().String()
+
+	h.mu.Lock()
+	h.logins[waitId] = &pendingLogin{
+		dischargeId: dischargeId,
+		done:        make(chan struct{}),
+	}
+	h.mu.Unlock()
+
+	visitURL := "/visit?" + waitIdParam + "=" + waitId
+	waitURL := "/wait?" + waitIdParam + "=" + waitId
+	apierror.WriteError(w, httpbakery.NewInteractionRequiredError(visitURL, waitURL, nil, req))
+}
+
+// visit presents the login form (GET) and processes the submitted
+// credentials (POST).
+func (h *handler) visit(w http.ResponseWriter, req *http.Request) {
+	waitId := req.URL.Query().Get(waitIdParam)
+	h.mu.Lock()
+	login, ok := h.logins[waitId]
+	h.mu.Unlock()
+	if !ok {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeNotFound, "unknown login"))
+		return
+	}
+
+	if req.Method == "GET" {
+		visitPage.Execute(w, struct{ WaitId string }{waitId})
+		return
+	}
+
+	if err := req.ParseForm(); err != nil {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeBadRequest, "bad request"))
+		return
+	}
+	username := req.PostForm.Get("username")
+	configuredUsername, err := h.config.UserStore.Username()
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
+	if username == "" || username != configuredUsername {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeForbidden, "forbidden"))
+		return
+	}
+
+	h.mu.Lock()
+	login.username = username
+	h.mu.Unlock()
+	close(login.done)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:  sessionCookieName,
+		Value: username,
+		Path:  "/",
+	})
+	writeResponse(w, http.StatusOK, "logged in, you may close this window")
+}
+
+// wait blocks until the login identified by waitid completes, then
+// discharges the original third party caveat for the now-known username.
+func (h *handler) wait(w http.ResponseWriter, req *http.Request) {
+	waitId := req.URL.Query().Get(waitIdParam)
+	h.mu.Lock()
+	login, ok := h.logins[waitId]
+	h.mu.Unlock()
+	if !ok {
+		apierror.WriteError(w, apierror.Newf(apierror.CodeNotFound, "unknown login"))
+		return
+	}
+
+	select {
+	case <-login.done:
+	case <-time.After(waitTimeout):
+		apierror.WriteError(w, apierror.Newf(apierror.CodeTimeout, "login timed out"))
+		return
+	}
+
+	h.mu.Lock()
+	delete(h.logins, waitId)
+	h.mu.Unlock()
+
+	m, err := h.config.Bakery.Discharge(
+		bakery.ThirdPartyCheckerFunc(
+			func(cavId, cav string) ([]checkers.Caveat, error) {
+				return h.checkThirdPartyCaveat(login.username, cavId, cav)
+			}),
+		login.dischargeId)
+	if err != nil {
+		apierror.WriteError(w, err)
+		return
+	}
+
+	response := struct {
+		Macaroon *macaroon.Macaroon
+	}{
+		Macaroon: m,
+	}
+	writeResponse(w, http.StatusOK, response)
+}
+
+// sessionUsername returns the username associated with the caller's
+// session cookie, if any.
+func sessionUsername(req *http.Request) (string, bool) {
+	cookie, err := req.Cookie(sessionCookieName)
+	if err != nil {
+		return "", false
+	}
+	return cookie.Value, true
+}
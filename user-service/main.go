@@ -10,9 +10,12 @@ import (
 	"github.com/gorilla/mux"
 	"gopkg.in/macaroon-bakery.v1/bakery"
 
+	"github.com/alesstimec/macaroon-demo/identity"
 	"github.com/alesstimec/macaroon-demo/user-service/handler"
 )
 
+const userFile = "user.yaml"
+
 func returnError(err error) {
 	fmt.Fprintf(os.Stderr, "%v", err)
 	os.Exit(1)
@@ -35,7 +38,8 @@ func main() {
 	fmt.Println("bakery created")
 
 	h := handler.NewHandler(handler.HandlerConfig{
-		Bakery: svc,
+		Bakery:    svc,
+		UserStore: identity.NewYAMLUserStore(userFile),
 	})
 	fmt.Println("handler created")
 